@@ -0,0 +1,174 @@
+package alfred
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache stores fetched values as JSON on disk under a Workflow's cache
+// directory, so repeated Alfred invocations (one per keystroke, typically)
+// don't have to re-fetch the same data within a TTL.
+type Cache struct {
+	dir   string
+	group singleflight.Group
+	wg    sync.WaitGroup
+}
+
+// Cache returns the Cache for w, storing its files under w.CacheDir().
+// Repeated calls return the same Cache, so the singleflight.Group backing
+// LoadOrStoreAsync is shared across callers.
+func (w *Workflow) Cache() *Cache {
+	if w.cache == nil {
+		w.cache = &Cache{dir: w.cacheDir}
+	}
+	return w.cache
+}
+
+// cacheEntry wraps a cached value together with the time it was stored, so
+// Age and the maxAge checks in LoadOrStore/LoadOrStoreAsync know how stale
+// it is.
+type cacheEntry struct {
+	Stored time.Time       `json:"stored"`
+	Value  json.RawMessage `json:"value"`
+}
+
+// path returns the file a cached value for name is stored under.
+func (c *Cache) path(name string) string {
+	return filepath.Join(c.dir, name+".json")
+}
+
+// Exists reports whether a cached value exists for name, regardless of how
+// old it is.
+func (c *Cache) Exists(name string) bool {
+	_, err := os.Stat(c.path(name))
+	return err == nil
+}
+
+// Age returns how long ago the cached value for name was stored. It
+// returns an error if no value is cached for name.
+func (c *Cache) Age(name string) (age time.Duration, err error) {
+	var entry cacheEntry
+	if err = c.load(name, &entry); err != nil {
+		return
+	}
+	age = time.Since(entry.Stored)
+	return
+}
+
+// Drop removes the cached value for name. It is not an error if no value
+// is cached for name.
+func (c *Cache) Drop(name string) error {
+	err := os.Remove(c.path(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// load reads and decodes the cacheEntry stored for name.
+func (c *Cache) load(name string, entry *cacheEntry) error {
+	data, err := os.ReadFile(c.path(name))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, entry)
+}
+
+// store encodes value as JSON and writes it to disk as the cacheEntry for
+// name, stamped with the current time.
+func (c *Cache) store(name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(&cacheEntry{Stored: time.Now(), Value: data})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.path(name), raw, 0644)
+}
+
+// LoadOrStore decodes the cached value for name into out, if one exists
+// and is younger than maxAge. Otherwise it calls fetch, stores the result
+// under name, and decodes it into out instead. fresh reports whether fetch
+// was called.
+func (c *Cache) LoadOrStore(name string, maxAge time.Duration, fetch func() (interface{}, error), out interface{}) (fresh bool, err error) {
+	var entry cacheEntry
+	if lerr := c.load(name, &entry); lerr == nil && time.Since(entry.Stored) < maxAge {
+		err = json.Unmarshal(entry.Value, out)
+		return
+	}
+
+	var value interface{}
+	if value, err = fetch(); err != nil {
+		return
+	}
+	fresh = true
+
+	if err = c.store(name, value); err != nil {
+		return
+	}
+
+	var data []byte
+	if data, err = json.Marshal(value); err != nil {
+		return
+	}
+	err = json.Unmarshal(data, out)
+	return
+}
+
+// LoadOrStoreAsync behaves like LoadOrStore, except that a cached value
+// older than maxAge is still decoded into out immediately (stale is true)
+// while fetch runs in the background to refresh it. Concurrent calls for
+// the same name share a single background fetch via a singleflight.Group.
+// If no cached value exists at all, LoadOrStoreAsync falls back to
+// LoadOrStore and blocks on fetch.
+//
+// A workflow binary is a short-lived process invoked once per Alfred
+// event, so nothing else keeps it alive long enough for a background
+// refresh to finish; callers must invoke Wait before the process exits, or
+// the refresh started here is silently abandoned.
+func (c *Cache) LoadOrStoreAsync(name string, maxAge time.Duration, fetch func() (interface{}, error), out interface{}) (stale bool, err error) {
+	var entry cacheEntry
+	if lerr := c.load(name, &entry); lerr != nil {
+		_, err = c.LoadOrStore(name, maxAge, fetch, out)
+		return
+	} else if err = json.Unmarshal(entry.Value, out); err != nil {
+		return
+	}
+
+	if time.Since(entry.Stored) >= maxAge {
+		stale = true
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			_, ferr := c.group.Do(name, func() (interface{}, error) {
+				value, ferr := fetch()
+				if ferr != nil {
+					return nil, ferr
+				}
+				return value, c.store(name, value)
+			})
+			if ferr != nil {
+				dlog.Printf("Error refreshing cached value for %q: %v", name, ferr)
+			}
+		}()
+	}
+
+	return
+}
+
+// Wait blocks until every background refresh started by LoadOrStoreAsync
+// has finished. Run calls Wait on Workflow.Cache() after dispatching to a
+// Command, so a refresh that LoadOrStoreAsync kicked off actually lands on
+// disk before the process exits.
+func (c *Cache) Wait() {
+	c.wg.Wait()
+}
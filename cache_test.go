@@ -0,0 +1,109 @@
+package alfred
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCacheLoadOrStoreFetchesOnceWithinMaxAge(t *testing.T) {
+	c := &Cache{dir: t.TempDir()}
+
+	calls := 0
+	fetch := func() (interface{}, error) {
+		calls++
+		return "value", nil
+	}
+
+	var out string
+	if _, err := c.LoadOrStore("name", time.Minute, fetch, &out); err != nil {
+		t.Fatalf("LoadOrStore: %v", err)
+	}
+	if _, err := c.LoadOrStore("name", time.Minute, fetch, &out); err != nil {
+		t.Fatalf("LoadOrStore: %v", err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("fetch called %d times, want 1", calls)
+	}
+	if out != "value" {
+		t.Fatalf("out = %q, want %q", out, "value")
+	}
+}
+
+func TestCacheLoadOrStoreAsyncRefreshesStaleValueInBackground(t *testing.T) {
+	c := &Cache{dir: t.TempDir()}
+
+	if err := c.store("name", "stale"); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	calls := 0
+	fetch := func() (interface{}, error) {
+		calls++
+		return "fresh", nil
+	}
+
+	var out string
+	stale, err := c.LoadOrStoreAsync("name", 0, fetch, &out)
+	if err != nil {
+		t.Fatalf("LoadOrStoreAsync: %v", err)
+	}
+	if !stale {
+		t.Fatal("stale = false, want true")
+	}
+	if out != "stale" {
+		t.Fatalf("out = %q, want the stale value %q", out, "stale")
+	}
+
+	c.Wait()
+
+	if calls != 1 {
+		t.Fatalf("fetch called %d times, want 1", calls)
+	}
+
+	var refreshed string
+	if age, err := c.Age("name"); err != nil || age >= time.Minute {
+		t.Fatalf("cached value wasn't refreshed: age=%v err=%v", age, err)
+	}
+	if _, err := c.LoadOrStore("name", time.Hour, func() (interface{}, error) {
+		t.Fatal("fetch should not run again; the refresh should already be stored")
+		return nil, nil
+	}, &refreshed); err != nil {
+		t.Fatalf("LoadOrStore: %v", err)
+	}
+	if refreshed != "fresh" {
+		t.Fatalf("refreshed = %q, want %q", refreshed, "fresh")
+	}
+}
+
+func TestCacheLoadOrStoreAsyncWaitReturnsAfterFailedRefresh(t *testing.T) {
+	c := &Cache{dir: t.TempDir()}
+
+	if err := c.store("name", "stale"); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	var out string
+	if _, err := c.LoadOrStoreAsync("name", 0, func() (interface{}, error) {
+		return nil, errors.New("upstream is down")
+	}, &out); err != nil {
+		t.Fatalf("LoadOrStoreAsync: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait never returned after a failed background refresh")
+	}
+
+	if out != "stale" {
+		t.Fatalf("out = %q, want the stale value to still be readable", out)
+	}
+}
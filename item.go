@@ -0,0 +1,260 @@
+package alfred
+
+import "encoding/json"
+
+// ItemIcon is the icon Alfred shows for an Item or ItemMod. Type is "" for
+// a plain image file, "fileicon" to use path's own file/bundle icon, or
+// "filetype" to look path up as a UTI.
+type ItemIcon struct {
+	Path string `json:"path"`
+	Type string `json:"type,omitempty"`
+}
+
+// ItemArg is the payload an Item or ItemMod passes on to the next
+// invocation of the workflow when it is actioned. Its fields mirror
+// workflowData, since that's what Run decodes it back into; any field left
+// at its zero value falls back to the ambient data Item.data was stamped
+// with by SendToAlfred.
+type ItemArg struct {
+	Keyword string
+	Mod     ModKey
+	Data    string
+}
+
+// Item is a single result Alfred shows to the user. Build one with NewItem
+// and the chainable methods below, or construct it as a literal (setting
+// Title, Subtitle, Autocomplete, and Arg directly) for simple cases.
+type Item struct {
+	Title        string
+	Subtitle     string
+	Autocomplete string
+	Arg          *ItemArg
+
+	uid       string
+	match     string
+	isValid   *bool
+	icon      *ItemIcon
+	quicklook string
+	action    []string
+	variables map[string]string
+	mods      map[ModKey]*ItemMod
+
+	data workflowData
+}
+
+// Items is a list of Item, as sent to Alfred in a single script-filter
+// response.
+type Items []Item
+
+// NewItem returns a new Item with the given title, ready for chaining the
+// rest of its fields.
+func NewItem(title string) *Item {
+	return &Item{Title: title}
+}
+
+// WithSubtitle sets the item's subtitle.
+func (i *Item) WithSubtitle(subtitle string) *Item {
+	i.Subtitle = subtitle
+	return i
+}
+
+// WithArg sets the arg passed to the next invocation of the workflow if
+// the item is actioned.
+func (i *Item) WithArg(arg *ItemArg) *Item {
+	i.Arg = arg
+	return i
+}
+
+// Var sets an Alfred workflow variable to be exported if the item is
+// actioned.
+func (i *Item) Var(key, value string) *Item {
+	if i.variables == nil {
+		i.variables = make(map[string]string)
+	}
+	i.variables[key] = value
+	return i
+}
+
+// Icon sets the item's icon.
+func (i *Item) Icon(path, iconType string) *Item {
+	i.icon = &ItemIcon{Path: path, Type: iconType}
+	return i
+}
+
+// Quicklook sets the URL or path Alfred shows when the user presses Shift
+// or taps the Quick Look key on this item.
+func (i *Item) Quicklook(url string) *Item {
+	i.quicklook = url
+	return i
+}
+
+// Match sets the text Alfred matches the user's query against, overriding
+// the default match against Title.
+func (i *Item) Match(match string) *Item {
+	i.match = match
+	return i
+}
+
+// UID sets the item's UID, which Alfred uses to learn the user's ranking
+// preference for this item over time. Items without a UID are not ranked.
+func (i *Item) UID(uid string) *Item {
+	i.uid = uid
+	return i
+}
+
+// Valid marks whether the item can be actioned. Items default to valid.
+func (i *Item) Valid(valid bool) *Item {
+	i.isValid = &valid
+	return i
+}
+
+// Action sets the item's Universal Action: a list of file paths, URLs, or
+// plain strings for Alfred to act on directly, bypassing Arg.
+func (i *Item) Action(universal []string) *Item {
+	i.action = universal
+	return i
+}
+
+// AddMod registers mod to override some of i's fields when key is held
+// down.
+func (i *Item) AddMod(key ModKey, mod ItemMod) {
+	if i.mods == nil {
+		i.mods = make(map[ModKey]*ItemMod)
+	}
+	i.mods[key] = &mod
+}
+
+// NewMod creates the ItemMod for key, registers it on i via AddMod, and
+// returns it for chaining its own setters.
+func (i *Item) NewMod(key ModKey) *ItemMod {
+	i.AddMod(key, ItemMod{})
+	return i.mods[key]
+}
+
+// resolvedArg returns the workflowData that should be encoded as the JSON
+// arg for i or one of its mods: i.data (the ambient context SendToAlfred
+// stamps every item with) overridden by whatever non-zero fields override
+// specifies.
+func (i Item) resolvedArg(override *ItemArg) workflowData {
+	wd := i.data
+	if override != nil {
+		if override.Keyword != "" {
+			wd.Keyword = override.Keyword
+		}
+		if override.Mod != "" {
+			wd.Mod = override.Mod
+		}
+		if override.Data != "" {
+			wd.Data = override.Data
+		}
+	}
+	return wd
+}
+
+// ItemMod overrides some of an Item's fields when the user holds down a
+// modifier key. Build one with Item.NewMod.
+type ItemMod struct {
+	subtitle  string
+	arg       *ItemArg
+	isValid   *bool
+	icon      *ItemIcon
+	variables map[string]string
+}
+
+// WithSubtitle sets the subtitle Alfred shows while this modifier is held.
+func (m *ItemMod) WithSubtitle(subtitle string) *ItemMod {
+	m.subtitle = subtitle
+	return m
+}
+
+// WithArg sets the arg passed on if the item is actioned while this
+// modifier is held.
+func (m *ItemMod) WithArg(arg *ItemArg) *ItemMod {
+	m.arg = arg
+	return m
+}
+
+// Valid marks whether the item can be actioned while this modifier is
+// held.
+func (m *ItemMod) Valid(valid bool) *ItemMod {
+	m.isValid = &valid
+	return m
+}
+
+// Icon sets the icon Alfred shows while this modifier is held.
+func (m *ItemMod) Icon(path, iconType string) *ItemMod {
+	m.icon = &ItemIcon{Path: path, Type: iconType}
+	return m
+}
+
+// Var sets an Alfred workflow variable exported only if the item is
+// actioned while this modifier is held.
+func (m *ItemMod) Var(key, value string) *ItemMod {
+	if m.variables == nil {
+		m.variables = make(map[string]string)
+	}
+	m.variables[key] = value
+	return m
+}
+
+// itemJSON is the wire format of a single entry in Alfred's 4/5
+// script-filter item schema.
+type itemJSON struct {
+	Title        string             `json:"title"`
+	Subtitle     string             `json:"subtitle,omitempty"`
+	Autocomplete string             `json:"autocomplete,omitempty"`
+	Arg          string             `json:"arg,omitempty"`
+	UID          string             `json:"uid,omitempty"`
+	Match        string             `json:"match,omitempty"`
+	Valid        *bool              `json:"valid,omitempty"`
+	Icon         *ItemIcon          `json:"icon,omitempty"`
+	Quicklookurl string             `json:"quicklookurl,omitempty"`
+	Action       []string           `json:"action,omitempty"`
+	Variables    map[string]string  `json:"variables,omitempty"`
+	Mods         map[ModKey]modJSON `json:"mods,omitempty"`
+}
+
+// modJSON is the wire format of an entry in itemJSON.Mods.
+type modJSON struct {
+	Subtitle  string            `json:"subtitle,omitempty"`
+	Arg       string            `json:"arg,omitempty"`
+	Valid     *bool             `json:"valid,omitempty"`
+	Icon      *ItemIcon         `json:"icon,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding i in the Alfred 4/5
+// script-filter item schema.
+func (i Item) MarshalJSON() ([]byte, error) {
+	wd := i.resolvedArg(i.Arg)
+
+	out := itemJSON{
+		Title:        i.Title,
+		Subtitle:     i.Subtitle,
+		Autocomplete: i.Autocomplete,
+		Arg:          Stringify(&wd),
+		UID:          i.uid,
+		Match:        i.match,
+		Valid:        i.isValid,
+		Icon:         i.icon,
+		Quicklookurl: i.quicklook,
+		Action:       i.action,
+		Variables:    i.variables,
+	}
+
+	if len(i.mods) > 0 {
+		out.Mods = make(map[ModKey]modJSON, len(i.mods))
+		for key, mod := range i.mods {
+			modWd := i.resolvedArg(mod.arg)
+			out.Mods[key] = modJSON{
+				Subtitle:  mod.subtitle,
+				Arg:       Stringify(&modWd),
+				Valid:     mod.isValid,
+				Icon:      mod.icon,
+				Variables: mod.variables,
+			}
+		}
+	}
+
+	return json.Marshal(&out)
+}
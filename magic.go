@@ -0,0 +1,186 @@
+package alfred
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MagicAction is a built-in, debugging-oriented command that Workflow.Run
+// matches by keyword (MagicPrefix()+name) before dispatching to a user
+// Command.
+type MagicAction interface {
+	// Description is shown as the Item's subtitle when the action's
+	// keyword is listed in tell mode.
+	Description() string
+	// Do performs the action.
+	Do(w *Workflow) error
+}
+
+// MagicItemer lets a MagicAction build its own Item for tell mode, e.g. to
+// reflect state that Description can't. Actions that don't implement it
+// get a generic Item built from their keyword and Description.
+type MagicItemer interface {
+	MagicAction
+	MagicItem(w *Workflow) Item
+}
+
+// RegisterMagic adds action to the set of magic actions Run recognises
+// under keyword w.MagicPrefix()+name, replacing any action already
+// registered under that name.
+func (w *Workflow) RegisterMagic(name string, action MagicAction) {
+	if w.magicActions == nil {
+		w.magicActions = make(map[string]MagicAction)
+	}
+	w.magicActions[name] = action
+}
+
+// registerDefaultMagic registers the set of magic actions every Workflow
+// gets for free: cache/data/log/reset/delcache/deldata, plus the update
+// checker, which uses the Updater configured by SetUpdater.
+func (w *Workflow) registerDefaultMagic() {
+	w.RegisterMagic(magicUpdateAction, updateMagicAction{})
+	w.RegisterMagic("cache", openDirMagicAction{label: "cache", dir: (*Workflow).CacheDir})
+	w.RegisterMagic("data", openDirMagicAction{label: "data", dir: (*Workflow).DataDir})
+	w.RegisterMagic("log", logMagicAction{})
+	w.RegisterMagic("reset", resetMagicAction{})
+	w.RegisterMagic("delcache", deleteDirMagicAction{label: "cache", dir: (*Workflow).CacheDir})
+	w.RegisterMagic("deldata", deleteDirMagicAction{label: "data", dir: (*Workflow).DataDir})
+}
+
+// lookupMagic returns the MagicAction registered for the full keyword
+// (MagicPrefix()+name), if any.
+func (w *Workflow) lookupMagic(keyword string) (MagicAction, bool) {
+	if w.magicPrefix == "" || !strings.HasPrefix(keyword, w.magicPrefix) {
+		return nil, false
+	}
+	action, ok := w.magicActions[strings.TrimPrefix(keyword, w.magicPrefix)]
+	return action, ok
+}
+
+// magicNames returns the names of w's registered magic actions, sorted for
+// stable display order.
+func (w *Workflow) magicNames() []string {
+	names := make([]string, 0, len(w.magicActions))
+	for name := range w.magicActions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// magicItem builds the Item to show for keyword's MagicAction in tell
+// mode, using MagicItemer.MagicItem if action implements it.
+func (w *Workflow) magicItem(keyword string, action MagicAction) Item {
+	if mi, ok := action.(MagicItemer); ok {
+		item := mi.MagicItem(w)
+		if item.Arg == nil {
+			item.Arg = &ItemArg{Keyword: keyword}
+		}
+		return item
+	}
+
+	return Item{
+		Title:        keyword,
+		Subtitle:     action.Description(),
+		Autocomplete: keyword,
+		Arg:          &ItemArg{Keyword: keyword},
+	}
+}
+
+// updateMagicAction checks for and installs workflow updates.
+type updateMagicAction struct{}
+
+func (updateMagicAction) Description() string {
+	return "Check for and install an update to this workflow"
+}
+
+func (updateMagicAction) Do(w *Workflow) error {
+	if err := w.CheckForUpdate(); err != nil {
+		return err
+	}
+	if !w.UpdateAvailable() {
+		return fmt.Errorf("no update available")
+	}
+	return w.Install()
+}
+
+func (updateMagicAction) MagicItem(w *Workflow) Item {
+	if w.UpdateAvailable() {
+		return Item{
+			Title:    fmt.Sprintf("Update available: %s", w.updateRelease.Version),
+			Subtitle: "Press Enter to install",
+		}
+	}
+	return Item{Title: "No update available"}
+}
+
+// openDirMagicAction opens one of the workflow's directories in Finder.
+type openDirMagicAction struct {
+	label string
+	dir   func(w *Workflow) string
+}
+
+func (a openDirMagicAction) Description() string {
+	return fmt.Sprintf("Open the workflow's %s directory", a.label)
+}
+
+func (a openDirMagicAction) Do(w *Workflow) error {
+	_, err := RunScript(fmt.Sprintf(`tell application "Finder" to open POSIX file %s`,
+		strconv.Quote(a.dir(w))))
+	return err
+}
+
+// logMagicAction opens the workflow's log file in Console.app.
+type logMagicAction struct{}
+
+func (logMagicAction) Description() string {
+	return "Open the workflow's log file in Console.app"
+}
+
+func (logMagicAction) Do(w *Workflow) error {
+	_, err := RunScript(fmt.Sprintf(`tell application "Console" to open POSIX file %s`,
+		strconv.Quote(dlog.Path())))
+	return err
+}
+
+// deleteDirMagicAction deletes one of the workflow's directories without
+// confirmation.
+type deleteDirMagicAction struct {
+	label string
+	dir   func(w *Workflow) string
+}
+
+func (a deleteDirMagicAction) Description() string {
+	return fmt.Sprintf("Delete the workflow's %s directory", a.label)
+}
+
+func (a deleteDirMagicAction) Do(w *Workflow) error {
+	return os.RemoveAll(a.dir(w))
+}
+
+// resetMagicAction deletes both the workflow's cache and data directories,
+// after asking the user to confirm.
+type resetMagicAction struct{}
+
+func (resetMagicAction) Description() string {
+	return "Delete the workflow's cache and data directories"
+}
+
+func (resetMagicAction) Do(w *Workflow) error {
+	confirmed, err := w.GetConfirmation(
+		"Delete the workflow's cache and data directories?", false)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return nil
+	}
+
+	if err := os.RemoveAll(w.CacheDir()); err != nil {
+		return err
+	}
+	return os.RemoveAll(w.DataDir())
+}
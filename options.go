@@ -0,0 +1,94 @@
+package alfred
+
+// Option configures a Workflow created by OpenWorkflow.
+type Option func(*workflowConfig)
+
+// workflowConfig accumulates the Options passed to OpenWorkflow before a
+// Workflow is built from them.
+type workflowConfig struct {
+	createDirs   bool
+	maxResults   int
+	helpURL      string
+	logPrefix    string
+	maxLogSize   int64
+	sessionName  string
+	magicPrefix  string
+	fuzzyMatcher func(a, b string) bool
+}
+
+// defaultWorkflowConfig returns the workflowConfig used when no Options (or
+// only some) are passed to OpenWorkflow.
+func defaultWorkflowConfig() workflowConfig {
+	return workflowConfig{
+		sessionName:  "AW_SESSION_ID",
+		magicPrefix:  "workflow:",
+		fuzzyMatcher: FuzzyMatches,
+	}
+}
+
+// CreateDirs creates the workflow's cache and data directories if they
+// don't already exist. This is the Option equivalent of the createDirs
+// parameter accepted by the legacy OpenWorkflowLegacy.
+func CreateDirs(createDirs bool) Option {
+	return func(c *workflowConfig) {
+		c.createDirs = createDirs
+	}
+}
+
+// MaxResults limits the number of items SendToAlfred will emit for a
+// single Filter. When a Filter returns more than n items, SendToAlfred
+// truncates to n-1 and appends a "More results…" item that re-invokes the
+// same keyword with the next page. A value of 0 (the default) means no
+// limit.
+func MaxResults(n int) Option {
+	return func(c *workflowConfig) {
+		c.maxResults = n
+	}
+}
+
+// HelpURL sets the workflow's help URL, e.g. for a built-in "help" magic
+// action to open.
+func HelpURL(url string) Option {
+	return func(c *workflowConfig) {
+		c.helpURL = url
+	}
+}
+
+// LogPrefix sets the prefix dlog adds to each log line.
+func LogPrefix(prefix string) Option {
+	return func(c *workflowConfig) {
+		c.logPrefix = prefix
+	}
+}
+
+// MaxLogSize sets the size (in bytes) dlog's log file is allowed to grow
+// to before it is rotated.
+func MaxLogSize(n int64) Option {
+	return func(c *workflowConfig) {
+		c.maxLogSize = n
+	}
+}
+
+// SessionName sets the name of the Alfred workflow variable used to carry
+// the session ID between invocations. It defaults to "AW_SESSION_ID".
+func SessionName(name string) Option {
+	return func(c *workflowConfig) {
+		c.sessionName = name
+	}
+}
+
+// MagicPrefix sets the keyword prefix that identifies built-in magic
+// actions (see Workflow.RegisterMagic). It defaults to "workflow:".
+func MagicPrefix(prefix string) Option {
+	return func(c *workflowConfig) {
+		c.magicPrefix = prefix
+	}
+}
+
+// FuzzyMatcher overrides the function Run uses to decide whether a
+// Command's keyword matches the user's input. It defaults to FuzzyMatches.
+func FuzzyMatcher(fn func(a, b string) bool) Option {
+	return func(c *workflowConfig) {
+		c.fuzzyMatcher = fn
+	}
+}
@@ -0,0 +1,75 @@
+package alfred
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// sessionsDir is the subdirectory of a Workflow's cache directory that
+// Session data is namespaced under.
+const sessionsDir = "_sessions"
+
+// Session is a Cache scoped to the current Alfred session. Run carries the
+// session ID forward as the Alfred workflow variable named by
+// Workflow.SessionName() (AW_SESSION_ID by default), reading it back with
+// os.Getenv on each invocation rather than from the JSON data blob, since a
+// Script Filter re-invoked by typing or Tab only ever receives a plain-text
+// query as argv. A new session is minted only once that variable is also
+// empty, i.e. once the window has been dismissed, so a Filter can cache
+// expensive per-query results across keystrokes but have them discarded as
+// soon as the user starts a fresh query.
+type Session struct {
+	Cache
+	// ID is this session's ID.
+	ID string
+}
+
+// Session returns the Session for w's current invocation, namespaced under
+// cacheDir/_sessions/<id>. Run assigns the session ID before dispatching
+// to any Command; called outside of Run, Session mints a one-off ID on
+// first use.
+func (w *Workflow) Session() *Session {
+	if w.sessionID == "" {
+		w.sessionID = newSessionID()
+	}
+
+	dir := filepath.Join(w.cacheDir, sessionsDir, w.sessionID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		dlog.Printf("Error creating session directory: %v", err)
+	}
+
+	return &Session{Cache: Cache{dir: dir}, ID: w.sessionID}
+}
+
+// gcSessions removes every session directory under cacheDir/_sessions
+// except keep, so a dismissed session's cached files don't linger forever.
+func (w *Workflow) gcSessions(keep string) {
+	root := filepath.Join(w.cacheDir, sessionsDir)
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.Name() == keep {
+			continue
+		}
+		os.RemoveAll(filepath.Join(root, entry.Name()))
+	}
+}
+
+// newSessionID returns a random RFC 4122 version 4 UUID.
+func newSessionID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		dlog.Printf("Error generating session ID: %v", err)
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,72 @@
+package alfred
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestNewSessionIDLooksLikeAUUID(t *testing.T) {
+	re := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	a := newSessionID()
+	b := newSessionID()
+
+	if !re.MatchString(a) {
+		t.Fatalf("newSessionID() = %q, doesn't look like a v4 UUID", a)
+	}
+	if a == b {
+		t.Fatalf("two calls to newSessionID() returned the same ID: %q", a)
+	}
+}
+
+func TestGCSessionsKeepsOnlyTheGivenSession(t *testing.T) {
+	cacheDir := t.TempDir()
+	root := filepath.Join(cacheDir, sessionsDir)
+
+	keep := "keep-me"
+	for _, id := range []string{keep, "old-one", "old-two"} {
+		if err := os.MkdirAll(filepath.Join(root, id), 0755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+	}
+
+	w := &Workflow{cacheDir: cacheDir}
+	w.gcSessions(keep)
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != keep {
+		t.Fatalf("sessions after gc = %v, want only %q", entries, keep)
+	}
+}
+
+func TestGCSessionsIsANoOpWhenSessionsDirIsMissing(t *testing.T) {
+	w := &Workflow{cacheDir: t.TempDir()}
+
+	// Should not panic or error just because no Session has ever been
+	// created yet.
+	w.gcSessions("whatever")
+}
+
+func TestWorkflowSessionReturnsTheSameIDAcrossCalls(t *testing.T) {
+	w := &Workflow{cacheDir: t.TempDir()}
+
+	first := w.Session()
+	second := w.Session()
+
+	if first.ID == "" {
+		t.Fatal("Session().ID is empty")
+	}
+	if first.ID != second.ID {
+		t.Fatalf("Session().ID changed across calls on the same Workflow: %q != %q", first.ID, second.ID)
+	}
+
+	want := filepath.Join(w.cacheDir, sessionsDir, first.ID)
+	if first.dir != want {
+		t.Fatalf("Session().dir = %q, want %q", first.dir, want)
+	}
+}
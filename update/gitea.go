@@ -0,0 +1,68 @@
+package update
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Gitea checks a Gitea instance's releases for the newest .alfredworkflow
+// asset. It speaks the same releases API shape as GitHub.
+type Gitea struct {
+	// BaseURL is the root of the Gitea instance, e.g. "https://gitea.example.com".
+	BaseURL string
+	// Repo is "owner/name".
+	Repo string
+	// Prereleases includes pre-release versions when considering the
+	// newest release.
+	Prereleases bool
+	// Client is used to make requests. http.DefaultClient is used if nil.
+	Client httpClient
+}
+
+type giteaRelease struct {
+	TagName    string `json:"tag_name"`
+	Prerelease bool   `json:"prerelease"`
+	Assets     []struct {
+		Name        string `json:"name"`
+		DownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// LatestRelease implements Updater.
+func (u *Gitea) LatestRelease() (*Release, error) {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/releases", strings.TrimSuffix(u.BaseURL, "/"), u.Repo)
+
+	var raw []giteaRelease
+	if err := getJSON(u.Client, url, &raw); err != nil {
+		return nil, fmt.Errorf("update: fetching releases for %s: %w", u.Repo, err)
+	}
+
+	releases := make([]*Release, 0, len(raw))
+	for _, gr := range raw {
+		names := make([]string, len(gr.Assets))
+		for i, a := range gr.Assets {
+			names[i] = a.Name
+		}
+
+		name := alfredWorkflowAsset(names)
+		if name == "" {
+			continue
+		}
+
+		var downloadURL string
+		for _, a := range gr.Assets {
+			if a.Name == name {
+				downloadURL = a.DownloadURL
+				break
+			}
+		}
+
+		releases = append(releases, &Release{
+			Version:     gr.TagName,
+			DownloadURL: downloadURL,
+			Prerelease:  gr.Prerelease,
+		})
+	}
+
+	return newestRelease(releases, u.Prereleases)
+}
@@ -0,0 +1,63 @@
+package update
+
+import "fmt"
+
+// GitHubReleases checks a GitHub repository's releases for the newest
+// .alfredworkflow asset.
+type GitHubReleases struct {
+	// Repo is "owner/name".
+	Repo string
+	// Prereleases includes pre-release versions when considering the
+	// newest release.
+	Prereleases bool
+	// Client is used to make requests. http.DefaultClient is used if nil.
+	Client httpClient
+}
+
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Prerelease bool   `json:"prerelease"`
+	Assets     []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// LatestRelease implements Updater.
+func (u *GitHubReleases) LatestRelease() (*Release, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/releases", u.Repo)
+
+	var raw []githubRelease
+	if err := getJSON(u.Client, url, &raw); err != nil {
+		return nil, fmt.Errorf("update: fetching releases for %s: %w", u.Repo, err)
+	}
+
+	releases := make([]*Release, 0, len(raw))
+	for _, gr := range raw {
+		names := make([]string, len(gr.Assets))
+		for i, a := range gr.Assets {
+			names[i] = a.Name
+		}
+
+		name := alfredWorkflowAsset(names)
+		if name == "" {
+			continue
+		}
+
+		var downloadURL string
+		for _, a := range gr.Assets {
+			if a.Name == name {
+				downloadURL = a.BrowserDownloadURL
+				break
+			}
+		}
+
+		releases = append(releases, &Release{
+			Version:     gr.TagName,
+			DownloadURL: downloadURL,
+			Prerelease:  gr.Prerelease,
+		})
+	}
+
+	return newestRelease(releases, u.Prereleases)
+}
@@ -0,0 +1,53 @@
+package update
+
+import "fmt"
+
+// HTTPManifest checks a bare JSON manifest for the newest release. This is
+// the escape hatch for workflows that don't publish through GitHub or
+// Gitea: any server that can return a JSON document shaped like
+// manifestEntry works.
+type HTTPManifest struct {
+	// URL points at a JSON document: either a single release object or an
+	// array of them.
+	URL string
+	// Prereleases includes pre-release versions when considering the
+	// newest release.
+	Prereleases bool
+	// Client is used to make requests. http.DefaultClient is used if nil.
+	Client httpClient
+}
+
+type manifestEntry struct {
+	Version    string `json:"version"`
+	URL        string `json:"url"`
+	Checksum   string `json:"checksum"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// LatestRelease implements Updater.
+func (u *HTTPManifest) LatestRelease() (*Release, error) {
+	var raw []manifestEntry
+	if err := getJSON(u.Client, u.URL, &raw); err != nil {
+		// Fall back to a single-object manifest.
+		var single manifestEntry
+		if err2 := getJSON(u.Client, u.URL, &single); err2 != nil {
+			return nil, fmt.Errorf("update: fetching manifest %s: %w", u.URL, err)
+		}
+		raw = []manifestEntry{single}
+	}
+
+	releases := make([]*Release, 0, len(raw))
+	for _, m := range raw {
+		if m.URL == "" {
+			continue
+		}
+		releases = append(releases, &Release{
+			Version:     m.Version,
+			DownloadURL: m.URL,
+			Checksum:    m.Checksum,
+			Prerelease:  m.Prerelease,
+		})
+	}
+
+	return newestRelease(releases, u.Prereleases)
+}
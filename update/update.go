@@ -0,0 +1,148 @@
+// Package update provides self-update functionality for Alfred workflows.
+//
+// An Updater checks some release source (GitHub, Gitea, or a bare HTTP JSON
+// manifest) for a newer version of the workflow than the one currently
+// installed, and returns the asset that should be downloaded and handed off
+// to Alfred for installation.
+package update
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Release describes a single available release of a workflow.
+type Release struct {
+	// Version is the release's semantic version, e.g. "1.4.0".
+	Version string
+	// DownloadURL points at the .alfredworkflow asset for this release.
+	DownloadURL string
+	// Checksum is an optional sha256 checksum (hex-encoded) for the asset
+	// at DownloadURL. If empty, the asset is not verified.
+	Checksum string
+	// Prerelease is true if this release is a pre-release.
+	Prerelease bool
+}
+
+// Updater finds the newest available release of a workflow.
+type Updater interface {
+	// LatestRelease returns the newest release matching the Updater's
+	// configured constraints, or an error if none could be found.
+	LatestRelease() (*Release, error)
+}
+
+// VerifyChecksum reports whether data matches rel.Checksum. If rel.Checksum
+// is empty, no verification is possible and VerifyChecksum returns true.
+func VerifyChecksum(rel *Release, data []byte) bool {
+	if rel.Checksum == "" {
+		return true
+	}
+	sum := sha256.Sum256(data)
+	return strings.EqualFold(hex.EncodeToString(sum[:]), rel.Checksum)
+}
+
+// httpClient is the subset of *http.Client used by the updaters in this
+// package, so tests can substitute a fake.
+type httpClient interface {
+	Get(url string) (*http.Response, error)
+}
+
+func getJSON(client httpClient, url string, out interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("update: unexpected status %s from %s", resp.Status, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// alfredWorkflowAsset returns the first asset name ending in
+// ".alfredworkflow".
+func alfredWorkflowAsset(names []string) string {
+	for _, name := range names {
+		if strings.HasSuffix(name, ".alfredworkflow") {
+			return name
+		}
+	}
+	return ""
+}
+
+// version is a minimal, dependency-free semantic version, good enough to
+// sort and compare the tags used by GitHub/Gitea releases.
+type version struct {
+	raw   string
+	parts []int
+}
+
+func parseVersion(s string) version {
+	s = strings.TrimPrefix(s, "v")
+	fields := strings.SplitN(s, "-", 2)
+	parts := make([]int, 0, 3)
+	for _, f := range strings.Split(fields[0], ".") {
+		n, _ := strconv.Atoi(f)
+		parts = append(parts, n)
+	}
+	return version{raw: s, parts: parts}
+}
+
+// less reports whether v is older than other.
+func (v version) less(other version) bool {
+	for i := 0; i < len(v.parts) || i < len(other.parts); i++ {
+		var a, b int
+		if i < len(v.parts) {
+			a = v.parts[i]
+		}
+		if i < len(other.parts) {
+			b = other.parts[i]
+		}
+		if a != b {
+			return a < b
+		}
+	}
+	return false
+}
+
+// IsNewer reports whether candidate is a newer version than current, using
+// the same lenient semver comparison newestRelease sorts releases with.
+func IsNewer(current, candidate string) bool {
+	return parseVersion(current).less(parseVersion(candidate))
+}
+
+// newestRelease returns the newest entry in releases. Prereleases are
+// skipped unless allowPrerelease is true, in which case they are compared
+// alongside stable releases and a newer prerelease wins over an older
+// stable one.
+func newestRelease(releases []*Release, allowPrerelease bool) (*Release, error) {
+	candidates := make([]*Release, 0, len(releases))
+	for _, r := range releases {
+		if r.Prerelease && !allowPrerelease {
+			continue
+		}
+		candidates = append(candidates, r)
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("update: no matching releases found")
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return parseVersion(candidates[i].Version).less(parseVersion(candidates[j].Version))
+	})
+
+	return candidates[len(candidates)-1], nil
+}
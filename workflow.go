@@ -5,13 +5,29 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"text/template"
+	"time"
+
+	"github.com/denavramenko/go-alfred/update"
 )
 
+// DefaultUpdateInterval is the interval CheckForUpdate waits between
+// actually contacting the configured Updater, used when SetUpdater is
+// called with an interval of 0.
+const DefaultUpdateInterval = 24 * time.Hour
+
+// magicUpdateAction is the keyword (after the configured magic prefix) of
+// the built-in magic action that checks for and installs workflow updates.
+const magicUpdateAction = "update"
+
 // ModKey is a modifier key (e.g., cmd, ctrl, alt)
 type ModKey string
 
@@ -86,17 +102,41 @@ type Workflow struct {
 	bundleID string
 	cacheDir string
 	dataDir  string
+
+	updater        update.Updater
+	currentVersion string
+	updateInterval time.Duration
+	updateRelease  *update.Release
+
+	panicHandler func(interface{})
+
+	maxResults   int
+	helpURL      string
+	sessionName  string
+	magicPrefix  string
+	fuzzyMatcher func(a, b string) bool
+	magicActions map[string]MagicAction
+
+	cache     *Cache
+	sessionID string
+	rerun     float64
 }
 
-// OpenWorkflow returns a Workflow for a given directory. If the createDirs
-// option is true, cache and data directories will be created for the workflow.
-func OpenWorkflow(workflowDir string, createDirs bool) (w Workflow, err error) {
+// OpenWorkflow returns a Workflow, configured by the given Options. See
+// CreateDirs, MaxResults, HelpURL, LogPrefix, MaxLogSize, SessionName,
+// MagicPrefix, and FuzzyMatcher.
+func OpenWorkflow(opts ...Option) (w Workflow, err error) {
+	cfg := defaultWorkflowConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	bundleID := os.Getenv("alfred_workflow_bundleid")
 	name := os.Getenv("alfred_workflow_name")
 	cacheDir := os.Getenv("alfred_workflow_cache")
 	dataDir := os.Getenv("alfred_workflow_data")
 
-	if createDirs {
+	if cfg.createDirs {
 		if err = os.MkdirAll(cacheDir, 0755); err != nil {
 			return
 		}
@@ -105,36 +145,59 @@ func OpenWorkflow(workflowDir string, createDirs bool) (w Workflow, err error) {
 		}
 	}
 
+	if cfg.logPrefix != "" {
+		dlog.SetPrefix(cfg.logPrefix)
+	}
+	if cfg.maxLogSize > 0 {
+		dlog.SetMaxSize(cfg.maxLogSize)
+	}
+
 	w = Workflow{
-		name:     name,
-		bundleID: bundleID,
-		cacheDir: cacheDir,
-		dataDir:  dataDir,
+		name:         name,
+		bundleID:     bundleID,
+		cacheDir:     cacheDir,
+		dataDir:      dataDir,
+		maxResults:   cfg.maxResults,
+		helpURL:      cfg.helpURL,
+		sessionName:  cfg.sessionName,
+		magicPrefix:  cfg.magicPrefix,
+		fuzzyMatcher: cfg.fuzzyMatcher,
 	}
+	w.registerDefaultMagic()
 
 	return
 }
 
+// OpenWorkflowLegacy returns a Workflow for a given directory, using the
+// calling convention OpenWorkflow had before Option was introduced.
+// workflowDir is accepted for historical reasons but, as before, unused:
+// the workflow's directories are discovered from the alfred_workflow_*
+// environment variables.
+//
+// Deprecated: use OpenWorkflow with the CreateDirs Option instead.
+func OpenWorkflowLegacy(workflowDir string, createDirs bool) (w Workflow, err error) {
+	return OpenWorkflow(CreateDirs(createDirs))
+}
+
 // Run runs a workflow.
 //
 // A Workflow understands the following command line formats
 //
-//  $ ./workflow (arg|data)
-//  $ ./workflow arg data
-//  $ ./workflow -final data
+//	$ ./workflow (arg|data)
+//	$ ./workflow arg data
+//	$ ./workflow -final data
 //
 // Run takes one parameter: a list of Commands. Commands may be Filters or
 // Actions. Filters are commands that generate lists of items, while Actions
 // are commands that take an action.
 //
 // When the mode is "tell"...
-//   * ...and a keyword was specified in the incoming data, the Filter matching
+//   - ...and a keyword was specified in the incoming data, the Filter matching
 //     that keyword (if there is one) is called to generate items
-//   * ...and no keyword was specified in the incoming data, items are generated
+//   - ...and no keyword was specified in the incoming data, items are generated
 //     for:
-//     * any Filter with a fuzzy-matching keyword
-//     * any Action with a fuzzy-matching keyword and an Arg in its CommandDef
-//
+//   - any Filter with a fuzzy-matching keyword
+//   - any Action with a fuzzy-matching keyword and an Arg in its CommandDef
 func (w *Workflow) Run(commands []Command) {
 	var mode ModeType
 	var final bool
@@ -144,6 +207,37 @@ func (w *Workflow) Run(commands []Command) {
 	var prefix string
 	var err error
 
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		dlog.Printf("panic: %v\n%s", r, debug.Stack())
+
+		if w.panicHandler != nil {
+			w.panicHandler(r)
+		}
+
+		if data.Mode == ModeDo {
+			fmt.Printf("Error: %v\n", r)
+			os.Exit(1)
+		}
+
+		msg := fmt.Sprintf("%v", r)
+		w.SendToAlfred(Items{Item{Title: msg, Subtitle: msg}}, data)
+		os.Exit(1)
+	}()
+
+	// Block here, after everything else, so a refresh LoadOrStoreAsync
+	// started against w.cache actually finishes and lands on disk before
+	// this short-lived process exits.
+	defer func() {
+		if w.cache != nil {
+			w.cache.Wait()
+		}
+	}()
+
 	flag.BoolVar(&final, "final", false, "If true, act as the final workflow "+
 		"stage")
 	flag.Parse()
@@ -228,6 +322,36 @@ func (w *Workflow) Run(commands []Command) {
 		} else {
 			arg = strings.Trim(arg, " ")
 		}
+
+		// A Script Filter re-invoked by typing or by Tab gets a plain-text
+		// query as argv, never the JSON data blob, so data.Session is
+		// never populated that way past the very first keystroke. The
+		// session ID actually survives the keystroke loop as the
+		// sessionName Alfred workflow variable, which SendToAlfred
+		// exports and Alfred carries forward for as long as the current
+		// window stays open. Only fall back to minting a new one when
+		// that's empty too, meaning the window was dismissed and Alfred
+		// isn't carrying any exported variables forward at all.
+		if data.Session == "" {
+			data.Session = os.Getenv(w.sessionName)
+		}
+		if data.Session == "" {
+			data.Session = newSessionID()
+			w.gcSessions(data.Session)
+		}
+		w.sessionID = data.Session
+	}
+
+	// CheckForUpdate self-throttles against w.updateInterval and is a
+	// no-op without an Updater, so calling it on every invocation is
+	// cheap; it's what lets the "workflow:update" magic item (see
+	// updateMagicAction.MagicItem) ever report an update as available.
+	if err == nil {
+		w.CheckForUpdate()
+	}
+
+	if err == nil && w.handleMagic(keyword, data) {
+		return
 	}
 
 	switch data.Mode {
@@ -261,7 +385,7 @@ func (w *Workflow) Run(commands []Command) {
 							}
 						}
 					}
-				} else if FuzzyMatches(def.Keyword, keyword) {
+				} else if w.fuzzyMatch(def.Keyword, keyword) {
 					if _, ok := c.(Filter); ok || def.Arg != nil {
 						dlog.Printf("Adding menu item for '%s'", def.Keyword)
 						items = append(items, def.KeywordItem())
@@ -269,6 +393,15 @@ func (w *Workflow) Run(commands []Command) {
 				}
 			}
 
+			if data.Keyword == "" {
+				for _, name := range w.magicNames() {
+					magicKeyword := w.magicPrefix + name
+					if w.fuzzyMatch(magicKeyword, keyword) {
+						items = append(items, w.magicItem(magicKeyword, w.magicActions[name]))
+					}
+				}
+			}
+
 			if arg != "" {
 				FuzzySort(items, arg)
 			}
@@ -362,6 +495,24 @@ func (w *Workflow) BundleID() string {
 	return w.bundleID
 }
 
+// HelpURL returns the workflow's help URL, as set by the HelpURL Option.
+func (w *Workflow) HelpURL() string {
+	return w.helpURL
+}
+
+// SessionName returns the name of the Alfred workflow variable used to
+// carry the session ID between invocations, as set by the SessionName
+// Option.
+func (w *Workflow) SessionName() string {
+	return w.sessionName
+}
+
+// MagicPrefix returns the keyword prefix that identifies built-in magic
+// actions, as set by the MagicPrefix Option.
+func (w *Workflow) MagicPrefix() string {
+	return w.magicPrefix
+}
+
 // GetConfirmation opens a confirmation dialog to ask the user to confirm
 // something.
 func (w *Workflow) GetConfirmation(prompt string, defaultYes bool) (confirmed bool, err error) {
@@ -472,13 +623,224 @@ func (w *Workflow) GetPassword(name string) (pw string, err error) {
 	return
 }
 
-// SendToAlfred sends an array of items to Alfred. Currently this equates to
-// outputting an Alfred JSON message on stdout.
+// SetRerun tells Alfred to automatically re-invoke the script filter after
+// seconds have passed, even without further user input, until a later
+// response clears it by calling SetRerun(0). A Filter calls it from within
+// Items to poll for a result that isn't ready yet. See SendToAlfred.
+func (w *Workflow) SetRerun(seconds float64) {
+	w.rerun = seconds
+}
+
+// SetPanicHandler registers fn to be called with the recovered value
+// whenever Run catches a panic from a Filter.Items or Action.Do call. fn
+// runs before Run reports the panic to the user and exits, so it's the
+// place to hook in crash reporting.
+func (w *Workflow) SetPanicHandler(fn func(interface{})) {
+	w.panicHandler = fn
+}
+
+// SetUpdater configures w to check u for newer releases of the workflow, at
+// most once per interval, comparing them against currentVersion (e.g.
+// "1.4.0") so UpdateAvailable stops reporting a release once the user has
+// installed it. If interval is 0, DefaultUpdateInterval is used. The
+// "workflow:update" magic action is registered by OpenWorkflow whether or
+// not an Updater is set; it uses the Updater configured here, and without
+// one it reports that no update is available.
+func (w *Workflow) SetUpdater(u update.Updater, currentVersion string, interval time.Duration) {
+	if interval == 0 {
+		interval = DefaultUpdateInterval
+	}
+	w.updater = u
+	w.currentVersion = currentVersion
+	w.updateInterval = interval
+}
+
+// updateCheckFile returns the path of the file w uses to remember when it
+// last checked for an update.
+func (w *Workflow) updateCheckFile() string {
+	return filepath.Join(w.cacheDir, "_update_check.json")
+}
+
+// updateCheckState is persisted to updateCheckFile between runs.
+type updateCheckState struct {
+	Checked time.Time       `json:"checked"`
+	Release *update.Release `json:"release,omitempty"`
+}
+
+// CheckForUpdate asks the configured Updater for the latest release, but
+// only if more than the configured interval has passed since the last
+// check. The outcome is cached under Workflow.cacheDir and in memory, so
+// UpdateAvailable and Install can be called cheaply afterwards. It is a
+// no-op if no Updater has been set via SetUpdater. Run calls it on every
+// invocation, relying on this self-throttling to make that cheap.
+func (w *Workflow) CheckForUpdate() (err error) {
+	if w.updater == nil {
+		return
+	}
+
+	var state updateCheckState
+	if data, rerr := os.ReadFile(w.updateCheckFile()); rerr == nil {
+		json.Unmarshal(data, &state)
+	}
+
+	if time.Since(state.Checked) < w.updateInterval {
+		dlog.Printf("Skipping update check: last checked at %s", state.Checked)
+		w.updateRelease = state.Release
+		return
+	}
+
+	var rel *update.Release
+	if rel, err = w.updater.LatestRelease(); err != nil {
+		dlog.Printf("Error checking for update: %v", err)
+		return
+	}
+
+	w.updateRelease = rel
+
+	state = updateCheckState{Checked: time.Now(), Release: rel}
+	var data []byte
+	if data, err = json.Marshal(&state); err != nil {
+		return
+	}
+	err = os.WriteFile(w.updateCheckFile(), data, 0644)
+	return
+}
+
+// UpdateAvailable reports whether the last CheckForUpdate found a release
+// newer than the currentVersion passed to SetUpdater.
+func (w *Workflow) UpdateAvailable() bool {
+	return w.updateRelease != nil && update.IsNewer(w.currentVersion, w.updateRelease.Version)
+}
+
+// Install downloads the release found by CheckForUpdate, verifies its
+// checksum if one was supplied, and hands it off to Alfred by opening it.
+// Alfred installs a workflow when its .alfredworkflow file is opened.
+func (w *Workflow) Install() (err error) {
+	if w.updateRelease == nil {
+		return fmt.Errorf("no update available")
+	}
+	rel := w.updateRelease
+
+	var resp *http.Response
+	if resp, err = http.Get(rel.DownloadURL); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	if body, err = io.ReadAll(resp.Body); err != nil {
+		return
+	}
+
+	if !update.VerifyChecksum(rel, body) {
+		return fmt.Errorf("checksum mismatch for %s", rel.DownloadURL)
+	}
+
+	dest := filepath.Join(w.cacheDir, filepath.Base(rel.DownloadURL))
+	if err = os.WriteFile(dest, body, 0644); err != nil {
+		return
+	}
+
+	_, err = RunScript(fmt.Sprintf(`do shell script "open " & quoted form of %s`,
+		strconv.Quote(dest)))
+	return
+}
+
+// fuzzyMatch reports whether b fuzzy-matches a, using the FuzzyMatcher
+// Option the Workflow was opened with, or FuzzyMatches if none was given.
+func (w *Workflow) fuzzyMatch(a, b string) bool {
+	if w.fuzzyMatcher != nil {
+		return w.fuzzyMatcher(a, b)
+	}
+	return FuzzyMatches(a, b)
+}
+
+// handleMagic checks whether keyword names one of w's registered magic
+// actions (see RegisterMagic) and, if so, runs it directly instead of
+// dispatching to a user Command. It reports whether the keyword was
+// handled.
+func (w *Workflow) handleMagic(keyword string, data workflowData) bool {
+	action, ok := w.lookupMagic(keyword)
+	if !ok {
+		return false
+	}
+
+	if data.Mode == ModeDo {
+		if err := action.Do(w); err != nil {
+			fmt.Printf("Error: %s\n", err)
+		}
+		return true
+	}
+
+	w.SendToAlfred(Items{w.magicItem(keyword, action)}, data)
+	return true
+}
+
+// scriptFilterResponse is the Alfred 4/5 script-filter top-level JSON
+// response: items, plus the variables and rerun interval that apply
+// regardless of which item the user ends up actioning.
+type scriptFilterResponse struct {
+	Items     Items             `json:"items"`
+	Variables map[string]string `json:"variables,omitempty"`
+	Rerun     float64           `json:"rerun,omitempty"`
+}
+
+// SendToAlfred sends an array of items to Alfred as a script-filter JSON
+// response on stdout. items is the Filter's full, unpaginated result set;
+// SendToAlfred windows it to MaxResults starting at data.Page, appending a
+// "More results…" item whose own data carries the next page cursor, and
+// re-exports the current session ID (see Workflow.Session) as an Alfred
+// workflow variable. If data.Page windows past the end of items, SendToAlfred
+// falls back to a single message item rather than sending an empty list.
 func (w *Workflow) SendToAlfred(items Items, data workflowData) {
-	for _, item := range items {
-		item.data = data
+	var more *Item
+
+	if w.maxResults > 0 {
+		pageSize := w.maxResults - 1
+
+		start := data.Page * pageSize
+		if start > len(items) {
+			start = len(items)
+		}
+		items = items[start:]
+
+		if len(items) > w.maxResults {
+			hidden := len(items) - pageSize
+			items = items[:pageSize]
+
+			moreData := data
+			moreData.Page = data.Page + 1
+
+			item := Item{
+				Title:        "More results…",
+				Subtitle:     fmt.Sprintf("%d more result(s)", hidden),
+				Autocomplete: data.Keyword,
+			}
+			item.data = moreData
+			more = &item
+		}
 	}
-	out, _ := json.Marshal(items)
+
+	for idx := range items {
+		items[idx].data = data
+	}
+
+	if more != nil {
+		items = append(items, *more)
+	} else if len(items) == 0 {
+		// The pre-pagination items were non-empty (Run's own "No results"
+		// fallback already covers that case), but data.Page windowed past
+		// the end of them — the result set shrank between keystrokes, or
+		// a stale/replayed Page was replayed. Don't show a blank list.
+		items = append(items, Item{Title: "No more results"})
+	}
+
+	resp := scriptFilterResponse{Items: items, Rerun: w.rerun}
+	if w.sessionID != "" && w.sessionName != "" {
+		resp.Variables = map[string]string{w.sessionName: w.sessionID}
+	}
+
+	out, _ := json.Marshal(&resp)
 	fmt.Println(string(out))
 }
 
@@ -534,6 +896,12 @@ type workflowData struct {
 	Keyword string   `json:"keyword,omitempty"`
 	Mode    ModeType `json:"mode,omitempty"`
 	Mod     ModKey   `json:"mod,omitempty"`
+	// Page is the pagination cursor set by SendToAlfred's "More results…"
+	// item when MaxResults truncates a Filter's output.
+	Page int `json:"page,omitempty"`
+	// Session is the ID of the Alfred session this invocation belongs to.
+	// See Workflow.Session.
+	Session string `json:"session,omitempty"`
 	// Data is keyword-specific data
 	Data string `json:"data,omitempty"`
 }
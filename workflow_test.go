@@ -0,0 +1,139 @@
+package alfred
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+)
+
+// captureStdout runs fn with os.Stdout redirected to an in-memory pipe and
+// returns whatever it wrote, so SendToAlfred's output can be inspected
+// without actually going through Alfred.
+func captureStdout(t *testing.T, fn func()) []byte {
+	t.Helper()
+
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %v", err)
+	}
+	os.Stdout = w
+
+	fn()
+
+	w.Close()
+	os.Stdout = old
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading captured stdout: %v", err)
+	}
+	return out
+}
+
+// decodedResponse decodes a SendToAlfred response, and the per-item data
+// stashed in each item's JSON arg, so tests can assert on workflowData.Page
+// without reaching into Item's unexported fields.
+type decodedResponse struct {
+	Items []itemJSON `json:"items"`
+	Rerun float64    `json:"rerun,omitempty"`
+}
+
+func (r decodedResponse) itemData(t *testing.T, idx int) workflowData {
+	t.Helper()
+	var data workflowData
+	if err := json.Unmarshal([]byte(r.Items[idx].Arg), &data); err != nil {
+		t.Fatalf("decoding item %d arg %q: %v", idx, r.Items[idx].Arg, err)
+	}
+	return data
+}
+
+func sendToAlfred(t *testing.T, w *Workflow, items Items, data workflowData) decodedResponse {
+	t.Helper()
+
+	out := captureStdout(t, func() {
+		w.SendToAlfred(items, data)
+	})
+
+	var resp decodedResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("decoding response %s: %v", out, err)
+	}
+	return resp
+}
+
+func makeItems(n int) Items {
+	items := make(Items, n)
+	for i := range items {
+		items[i] = Item{Title: string(rune('a' + i))}
+	}
+	return items
+}
+
+func TestSendToAlfredPaginatesAcrossPages(t *testing.T) {
+	// maxResults 3 means 2 real items per page plus a "More results" slot,
+	// until what's left fits in a page without needing one.
+	w := &Workflow{maxResults: 3}
+	items := makeItems(7) // a..g
+
+	// Page 0: a, b, then "More results" for the remaining 5.
+	resp := sendToAlfred(t, w, items, workflowData{})
+	if len(resp.Items) != 3 {
+		t.Fatalf("page 0: got %d items, want 3", len(resp.Items))
+	}
+	if resp.Items[0].Title != "a" || resp.Items[1].Title != "b" {
+		t.Fatalf("page 0: got items %+v, want a, b, <more>", resp.Items)
+	}
+	more := resp.Items[2]
+	if more.Subtitle != "5 more result(s)" {
+		t.Fatalf("page 0: more item subtitle = %q, want '5 more result(s)'", more.Subtitle)
+	}
+	if page := resp.itemData(t, 2).Page; page != 1 {
+		t.Fatalf("page 0: more item's Page = %d, want 1", page)
+	}
+
+	// Page 1 (as carried by the previous page's "More results" item): c,
+	// d, then another "More results" for the remaining 3.
+	resp = sendToAlfred(t, w, items, workflowData{Page: 1})
+	if len(resp.Items) != 3 {
+		t.Fatalf("page 1: got %d items, want 3", len(resp.Items))
+	}
+	if resp.Items[0].Title != "c" || resp.Items[1].Title != "d" {
+		t.Fatalf("page 1: got items %+v, want c, d, <more>", resp.Items)
+	}
+	if page := resp.itemData(t, 2).Page; page != 2 {
+		t.Fatalf("page 1: more item's Page = %d, want 2", page)
+	}
+
+	// Page 2: the remaining 3 items (e, f, g) fit within maxResults, so no
+	// "More results" item is appended.
+	resp = sendToAlfred(t, w, items, workflowData{Page: 2})
+	if len(resp.Items) != 3 {
+		t.Fatalf("page 2: got %d items, want 3", len(resp.Items))
+	}
+	if resp.Items[0].Title != "e" || resp.Items[1].Title != "f" || resp.Items[2].Title != "g" {
+		t.Fatalf("page 2: got items %+v, want e, f, g with no more item", resp.Items)
+	}
+}
+
+func TestSendToAlfredFallsBackWhenPageIsPastTheEnd(t *testing.T) {
+	w := &Workflow{maxResults: 3}
+
+	resp := sendToAlfred(t, w, makeItems(5), workflowData{Page: 10})
+	if len(resp.Items) != 1 {
+		t.Fatalf("got %d items, want a single fallback item", len(resp.Items))
+	}
+	if resp.Items[0].Title == "" {
+		t.Fatal("fallback item has no title")
+	}
+}
+
+func TestSendToAlfredWithoutMaxResultsSendsEverything(t *testing.T) {
+	w := &Workflow{}
+
+	resp := sendToAlfred(t, w, makeItems(5), workflowData{})
+	if len(resp.Items) != 5 {
+		t.Fatalf("got %d items, want all 5 (no MaxResults configured)", len(resp.Items))
+	}
+}